@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/argoproj/pkg/stats"
 	"github.com/go-redis/redis/v8"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
 
 	cmdutil "github.com/argoproj/argo-cd/v2/cmd/util"
 	"github.com/argoproj/argo-cd/v2/common"
@@ -27,7 +32,7 @@ import (
 	"github.com/argoproj/argo-cd/v2/util/errors"
 	kubeutil "github.com/argoproj/argo-cd/v2/util/kube"
 	"github.com/argoproj/argo-cd/v2/util/settings"
-	"github.com/argoproj/argo-cd/v2/util/tls"
+	tlsutil "github.com/argoproj/argo-cd/v2/util/tls"
 )
 
 const (
@@ -35,15 +40,30 @@ const (
 	cliName = "argocd-application-controller"
 	// Default time in seconds for application resync period
 	defaultAppResyncPeriod = 180
+	// shardingAlgorithmLegacy is the original, static env-var driven
+	// modulo-hash shard assignment.
+	shardingAlgorithmLegacy = "legacy"
+	// shardingAlgorithmConsistentHash assigns clusters to replicas using a
+	// consistent hash ring built from live replica membership, so that
+	// scaling the controller only reshuffles ~1/N of clusters instead of
+	// all of them.
+	shardingAlgorithmConsistentHash = "consistent-hash"
+	// defaultSelfHealBackoffFactor is the multiplier applied to the
+	// self-heal backoff duration after each consecutive failed attempt.
+	defaultSelfHealBackoffFactor = 2.0
 )
 
 func NewCommand() *cobra.Command {
 	var (
 		clientConfig             clientcmd.ClientConfig
 		appResyncPeriod          int64
+		appResyncJitter          time.Duration
 		repoServerAddress        string
 		repoServerTimeoutSeconds int
 		selfHealTimeoutSeconds   int
+		selfHealBackoffInitial   time.Duration
+		selfHealBackoffMax       time.Duration
+		selfHealBackoffFactor    float64
 		statusProcessors         int
 		operationProcessors      int
 		glogLevel                int
@@ -54,6 +74,19 @@ func NewCommand() *cobra.Command {
 		redisClient              *redis.Client
 		repoServerPlaintext      bool
 		repoServerStrictTLS      bool
+		tlsReloadInterval        time.Duration
+		shardingAlgorithm        string
+		leaderElect              bool
+		leaderElectLeaseName     string
+		leaderElectLeaseDuration time.Duration
+		leaderElectRenewDeadline time.Duration
+		leaderElectRetryPeriod   time.Duration
+		kubeClientQPS            float32
+		kubeClientBurst          int
+		managedClusterQPS        float32
+		managedClusterBurst      int
+		workqueueRateLimitQPS    float64
+		workqueueRateLimitBucket int
 	)
 	var command = cobra.Command{
 		Use:               cliName,
@@ -68,6 +101,8 @@ func NewCommand() *cobra.Command {
 			config, err := clientConfig.ClientConfig()
 			errors.CheckError(err)
 			errors.CheckError(v1alpha1.SetK8SConfigDefaults(config))
+			config.QPS = kubeClientQPS
+			config.Burst = kubeClientBurst
 
 			kubeClient := kubernetes.NewForConfigOrDie(config)
 			appClient := appclientset.NewForConfigOrDie(config)
@@ -76,36 +111,65 @@ func NewCommand() *cobra.Command {
 			errors.CheckError(err)
 
 			resyncDuration := time.Duration(appResyncPeriod) * time.Second
+			selfHealBackoff := wait.Backoff{
+				Duration: selfHealBackoffInitial,
+				Factor:   selfHealBackoffFactor,
+				Cap:      selfHealBackoffMax,
+				Steps:    math.MaxInt32,
+			}
 			tlsConfig := apiclient.TLSConfiguration{
 				DisableTLS:       repoServerPlaintext,
 				StrictValidation: repoServerStrictTLS,
 			}
 
 			// Load CA information to use for validating connections to the
-			// repository server, if strict TLS validation was requested.
+			// repository server, if strict TLS validation was requested. A
+			// tlsutil.Watcher keeps this material fresh for the lifetime of
+			// the process so repo-server certs can be rotated without
+			// restarting the controller.
 			if !repoServerPlaintext && repoServerStrictTLS {
-				pool, err := tls.LoadX509CertPool(
-					fmt.Sprintf("%s/controller/tls/tls.crt", env.StringFromEnv(common.EnvAppConfigPath, common.DefaultAppConfigPath)),
-					fmt.Sprintf("%s/controller/tls/ca.crt", env.StringFromEnv(common.EnvAppConfigPath, common.DefaultAppConfigPath)),
-				)
+				certPath := fmt.Sprintf("%s/controller/tls/tls.crt", env.StringFromEnv(common.EnvAppConfigPath, common.DefaultAppConfigPath))
+				caPath := fmt.Sprintf("%s/controller/tls/ca.crt", env.StringFromEnv(common.EnvAppConfigPath, common.DefaultAppConfigPath))
+
+				pool, err := tlsutil.LoadX509CertPool(certPath, caPath)
 				if err != nil {
 					log.Fatalf("%v", err)
 				}
 				tlsConfig.Certificates = pool
-			}
 
-			repoClientset := apiclient.NewRepoServerClientset(repoServerAddress, repoServerTimeoutSeconds, tlsConfig)
+				tlsWatcher, err := tlsutil.NewWatcher(certPath, caPath, tlsReloadInterval)
+				if err != nil {
+					log.Fatalf("%v", err)
+				}
+				tlsConfig.Watcher = tlsWatcher
+			}
 
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
+			if tlsConfig.Watcher != nil {
+				go tlsConfig.Watcher.Run(ctx)
+			}
+
+			repoClientset := apiclient.NewRepoServerClientset(repoServerAddress, repoServerTimeoutSeconds, tlsConfig)
+
 			cache, err := cacheSrc()
 			errors.CheckError(err)
 			cache.Cache.SetClient(cacheutil.NewTwoLevelClient(cache.Cache.GetClient(), 10*time.Minute))
 
 			settingsMgr := settings.NewSettingsManager(ctx, kubeClient, namespace)
 			kubectl := kubeutil.NewKubectl()
-			clusterFilter := getClusterFilter()
+			workqueueRateLimiter := workqueue.NewMaxOfRateLimiter(
+				workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 1000*time.Second),
+				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(workqueueRateLimitQPS), workqueueRateLimitBucket)},
+			)
+			clusterFilter := getClusterFilter(ctx, kubeClient, namespace, shardingAlgorithm, leaderElectionOptions{
+				enabled:       leaderElect,
+				leaseName:     leaderElectLeaseName,
+				leaseDuration: leaderElectLeaseDuration,
+				renewDeadline: leaderElectRenewDeadline,
+				retryPeriod:   leaderElectRetryPeriod,
+			})
 			appController, err := controller.NewApplicationController(
 				namespace,
 				settingsMgr,
@@ -115,11 +179,16 @@ func NewCommand() *cobra.Command {
 				cache,
 				kubectl,
 				resyncDuration,
+				appResyncJitter,
 				time.Duration(selfHealTimeoutSeconds)*time.Second,
+				selfHealBackoff,
 				metricsPort,
 				metricsCacheExpiration,
 				kubectlParallelismLimit,
-				clusterFilter)
+				clusterFilter,
+				managedClusterQPS,
+				managedClusterBurst,
+				workqueueRateLimiter)
 			errors.CheckError(err)
 			cacheutil.CollectMetrics(redisClient, appController.GetMetricsServer())
 
@@ -148,16 +217,68 @@ func NewCommand() *cobra.Command {
 	command.Flags().IntVar(&metricsPort, "metrics-port", common.DefaultPortArgoCDMetrics, "Start metrics server on given port")
 	command.Flags().DurationVar(&metricsCacheExpiration, "metrics-cache-expiration", 0*time.Second, "Prometheus metrics cache expiration (disabled  by default. e.g. 24h0m0s)")
 	command.Flags().IntVar(&selfHealTimeoutSeconds, "self-heal-timeout-seconds", 5, "Specifies timeout between application self heal attempts")
+	command.Flags().DurationVar(&appResyncJitter, "app-resync-jitter", env.ParseDurationFromEnv("ARGOCD_RECONCILIATION_JITTER", 0, 0, math.MaxInt32), "Max duration to randomly offset each app's resync timer by, to avoid lockstep resyncs across the fleet.")
+	command.Flags().DurationVar(&selfHealBackoffInitial, "self-heal-backoff-initial", 5*time.Second, "Initial self heal retry backoff duration, used as the base of the exponential backoff.")
+	command.Flags().DurationVar(&selfHealBackoffMax, "self-heal-backoff-max", time.Minute, "Maximum self heal retry backoff duration.")
+	command.Flags().Float64Var(&selfHealBackoffFactor, "self-heal-backoff-factor", defaultSelfHealBackoffFactor, "Multiplier applied to the self heal backoff duration after each consecutive failure.")
 	command.Flags().Int64Var(&kubectlParallelismLimit, "kubectl-parallelism-limit", 20, "Number of allowed concurrent kubectl fork/execs. Any value less the 1 means no limit.")
 	command.Flags().BoolVar(&repoServerPlaintext, "repo-server-plaintext", false, "Disable TLS on connections to repo server")
 	command.Flags().BoolVar(&repoServerStrictTLS, "repo-server-strict-tls", false, "Whether to use strict validation of the TLS cert presented by the repo server")
+	command.Flags().DurationVar(&tlsReloadInterval, "tls-reload-interval", 5*time.Minute, "Periodic fallback interval to re-read repo-server TLS material from disk, in case a filesystem change notification is missed.")
+	command.Flags().StringVar(&shardingAlgorithm, "sharding-algorithm", env.StringFromEnv(common.EnvControllerShardingAlgorithm, shardingAlgorithmLegacy), "Specifies the algorithm used for cluster shard assignment. One of: legacy|consistent-hash")
+	command.Flags().BoolVar(&leaderElect, "leader-elect", false, "Use a per-replica coordination.k8s.io Lease, instead of StatefulSet pod listing, as the membership input to consistent-hash sharding. Lets HPA/StatefulSet scaling drive shard membership without setting ARGOCD_CONTROLLER_REPLICAS/ARGOCD_CONTROLLER_SHARD.")
+	command.Flags().StringVar(&leaderElectLeaseName, "leader-elect-lease-name", "argocd-application-controller", "Base name of the per-replica Lease used for membership; each replica holds \"<name>-<pod>\".")
+	command.Flags().DurationVar(&leaderElectLeaseDuration, "leader-elect-lease-duration", 15*time.Second, "Duration a replica's membership Lease is considered valid after its last renewal.")
+	command.Flags().DurationVar(&leaderElectRenewDeadline, "leader-elect-renew-deadline", 10*time.Second, "Timeout for a single attempt by a replica to renew its own membership Lease.")
+	command.Flags().DurationVar(&leaderElectRetryPeriod, "leader-elect-retry-period", 2*time.Second, "Interval at which each replica renews its own membership Lease.")
+	command.Flags().Float32Var(&kubeClientQPS, "kube-client-qps", floatFromEnv("ARGOCD_CONTROLLER_KUBE_CLIENT_QPS", 50), "QPS limit for the controller's local Kubernetes client.")
+	command.Flags().IntVar(&kubeClientBurst, "kube-client-burst", env.ParseNumFromEnv("ARGOCD_CONTROLLER_KUBE_CLIENT_BURST", 100, 0, math.MaxInt32), "Burst limit for the controller's local Kubernetes client.")
+	command.Flags().Float32Var(&managedClusterQPS, "managed-cluster-qps", floatFromEnv("ARGOCD_CONTROLLER_MANAGED_CLUSTER_QPS", 50), "QPS limit for clients talking to managed clusters.")
+	command.Flags().IntVar(&managedClusterBurst, "managed-cluster-burst", env.ParseNumFromEnv("ARGOCD_CONTROLLER_MANAGED_CLUSTER_BURST", 100, 0, math.MaxInt32), "Burst limit for clients talking to managed clusters.")
+	command.Flags().Float64Var(&workqueueRateLimitQPS, "workqueue-rate-limit-qps", 10, "Steady-state QPS limit for the application controller's workqueues.")
+	command.Flags().IntVar(&workqueueRateLimitBucket, "workqueue-rate-limit-bucket", 100, "Burst bucket size for the application controller's workqueues.")
 	cacheSrc = appstatecache.AddCacheFlagsToCmd(&command, func(client *redis.Client) {
 		redisClient = client
 	})
 	return &command
 }
 
-func getClusterFilter() func(cluster *v1alpha1.Cluster) bool {
+// leaderElectionOptions configures Lease-based membership for the
+// consistent-hash sharding algorithm. Despite the name, no single replica
+// is elected exclusive leader: every replica holds its own Lease, and the
+// live Lease set as a whole is the membership input to the shard manager.
+type leaderElectionOptions struct {
+	enabled       bool
+	leaseName     string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+func getClusterFilter(ctx context.Context, kubeClient *kubernetes.Clientset, namespace, algorithm string, leOpts leaderElectionOptions) func(cluster *v1alpha1.Cluster) bool {
+	if algorithm == shardingAlgorithmConsistentHash {
+		return getConsistentHashClusterFilter(ctx, kubeClient, namespace, leOpts)
+	}
+	return getLegacyClusterFilter()
+}
+
+// floatFromEnv parses a float32 flag default from an environment variable,
+// mirroring the numeric/duration env-var fallbacks used elsewhere in this
+// command for values that don't fit util/env's integer/duration helpers.
+func floatFromEnv(envVar string, defaultValue float32) float32 {
+	val := env.StringFromEnv(envVar, "")
+	if val == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(val, 32)
+	if err != nil {
+		log.Warnf("could not parse %s (%q) as float, using default %v: %v", envVar, val, defaultValue, err)
+		return defaultValue
+	}
+	return float32(parsed)
+}
+
+func getLegacyClusterFilter() func(cluster *v1alpha1.Cluster) bool {
 	replicas := env.ParseNumFromEnv(common.EnvControllerReplicas, 0, 0, math.MaxInt32)
 	shard := env.ParseNumFromEnv(common.EnvControllerShard, -1, -math.MaxInt32, math.MaxInt32)
 	var clusterFilter func(cluster *v1alpha1.Cluster) bool
@@ -174,3 +295,28 @@ func getClusterFilter() func(cluster *v1alpha1.Cluster) bool {
 	}
 	return clusterFilter
 }
+
+// getConsistentHashClusterFilter starts a sharding.ShardManager that tracks
+// live controller replicas and returns a filter backed by its atomic ring
+// pointer, so that the set of clusters owned by this replica is rebalanced
+// in place on scale-up/down instead of requiring a full restart.
+func getConsistentHashClusterFilter(ctx context.Context, kubeClient *kubernetes.Clientset, namespace string, leOpts leaderElectionOptions) func(cluster *v1alpha1.Cluster) bool {
+	self, err := os.Hostname()
+	errors.CheckError(err)
+
+	var membership sharding.MembershipSource
+	if leOpts.enabled {
+		leaseMembership := sharding.NewLeaseMembership(kubeClient, namespace, self, leOpts.leaseName, leOpts.leaseDuration, leOpts.renewDeadline, leOpts.retryPeriod)
+		go leaseMembership.Run(ctx)
+		membership = leaseMembership
+	} else {
+		selector := env.StringFromEnv(common.EnvControllerReplicasSelector, "app.kubernetes.io/name=argocd-application-controller")
+		membership = sharding.NewStatefulSetMembership(kubeClient, namespace, selector)
+	}
+
+	shardManager := sharding.NewShardManager(self, membership, 30*time.Second)
+	go shardManager.Run(ctx)
+
+	log.Infof("Processing clusters using consistent-hash sharding (self=%s)", self)
+	return shardManager.ClusterFilter()
+}
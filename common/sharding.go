@@ -0,0 +1,12 @@
+package common
+
+// EnvControllerShardingAlgorithm is the environment variable (and flag
+// default source) used to select the cluster shard assignment algorithm
+// for the application controller. Supported values are "legacy" and
+// "consistent-hash" (see the argocd-application-controller command).
+const EnvControllerShardingAlgorithm = "ARGOCD_CONTROLLER_SHARDING_ALGORITHM"
+
+// EnvControllerReplicasSelector is the label selector used to discover
+// the live application controller pods backing a StatefulSet, when
+// sharding membership is derived from pod listing rather than Leases.
+const EnvControllerReplicasSelector = "ARGOCD_CONTROLLER_REPLICAS_SELECTOR"
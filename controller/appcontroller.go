@@ -0,0 +1,309 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	appclientset "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
+	"github.com/argoproj/argo-cd/v2/reposerver/apiclient"
+	appstatecache "github.com/argoproj/argo-cd/v2/util/cache/appstate"
+	kubeutil "github.com/argoproj/argo-cd/v2/util/kube"
+	"github.com/argoproj/argo-cd/v2/util/settings"
+)
+
+// MetricsServer exposes the controller's Prometheus metrics, including
+// the workqueue_* series registered against workqueueMetricsProvider.
+type MetricsServer struct {
+	server *http.Server
+}
+
+// ApplicationController continuously reconciles Applications against
+// their live cluster state. Application and operation work is processed
+// off two rate-limited workqueues so that a burst of changes (e.g. a
+// webhook storm) is smoothed rather than hammering the repo-server and
+// target clusters all at once.
+type ApplicationController struct {
+	namespace               string
+	settingsMgr             *settings.SettingsManager
+	kubeClientset           kubernetes.Interface
+	applicationClientset    appclientset.Interface
+	repoClientset           *apiclient.Clientset
+	cache                   *appstatecache.Cache
+	kubectl                 kubeutil.Kubectl
+	appResyncPeriod         time.Duration
+	appResyncJitter         time.Duration
+	selfHealTimeout         time.Duration
+	selfHealBackoff         wait.Backoff
+	metricsServer           *MetricsServer
+	kubectlParallelismLimit int64
+	clusterFilter           func(*v1alpha1.Cluster) bool
+	managedClusterQPS       float32
+	managedClusterBurst     int
+
+	appQueue       workqueue.RateLimitingInterface
+	operationQueue workqueue.RateLimitingInterface
+
+	selfHeal *selfHealTracker
+}
+
+// NewApplicationController constructs an ApplicationController.
+//
+// appResyncJitter caps a per-app random offset added to appResyncPeriod,
+// so that applications don't all re-sync in lockstep (see jitteredResyncPeriod).
+// selfHealBackoff is the exponential backoff applied between consecutive
+// failed self-heal attempts for the same app, capped at selfHealBackoff.Cap
+// instead of the previous flat selfHealTimeout (see selfHealTracker).
+// managedClusterQPS/Burst are applied to the per-cluster REST configs
+// built by managedClusterRestConfig; no client is built from those configs. workqueueRateLimiter backs both the app and
+// operation queues in place of workqueue.DefaultControllerRateLimiter(),
+// and is paired with workqueueMetricsProvider so each queue's
+// depth/latency/retries are exported as workqueue_* Prometheus series.
+func NewApplicationController(
+	namespace string,
+	settingsMgr *settings.SettingsManager,
+	kubeClientset kubernetes.Interface,
+	applicationClientset appclientset.Interface,
+	repoClientset *apiclient.Clientset,
+	cache *appstatecache.Cache,
+	kubectl kubeutil.Kubectl,
+	appResyncPeriod time.Duration,
+	appResyncJitter time.Duration,
+	selfHealTimeout time.Duration,
+	selfHealBackoff wait.Backoff,
+	metricsPort int,
+	metricsCacheExpiration time.Duration,
+	kubectlParallelismLimit int64,
+	clusterFilter func(cluster *v1alpha1.Cluster) bool,
+	managedClusterQPS float32,
+	managedClusterBurst int,
+	workqueueRateLimiter workqueue.RateLimiter,
+) (*ApplicationController, error) {
+	workqueue.SetProvider(workqueueMetricsProvider{})
+
+	ctrl := &ApplicationController{
+		namespace:               namespace,
+		settingsMgr:             settingsMgr,
+		kubeClientset:           kubeClientset,
+		applicationClientset:    applicationClientset,
+		repoClientset:           repoClientset,
+		cache:                   cache,
+		kubectl:                 kubectl,
+		appResyncPeriod:         appResyncPeriod,
+		appResyncJitter:         appResyncJitter,
+		selfHealTimeout:         selfHealTimeout,
+		selfHealBackoff:         selfHealBackoff,
+		metricsServer:           newMetricsServer(metricsPort),
+		kubectlParallelismLimit: kubectlParallelismLimit,
+		clusterFilter:           clusterFilter,
+		managedClusterQPS:       managedClusterQPS,
+		managedClusterBurst:     managedClusterBurst,
+		appQueue:                workqueue.NewNamedRateLimitingQueue(workqueueRateLimiter, "app-reconciliation-queue"),
+		operationQueue:          workqueue.NewNamedRateLimitingQueue(workqueueRateLimiter, "app-operation-queue"),
+		selfHeal:                newSelfHealTracker(selfHealBackoff),
+	}
+	return ctrl, nil
+}
+
+// GetMetricsServer returns the controller's metrics server.
+func (ctrl *ApplicationController) GetMetricsServer() *MetricsServer {
+	return ctrl.metricsServer
+}
+
+// Run starts statusProcessors workers draining the app queue and
+// operationProcessors workers draining the operation queue, and blocks
+// until ctx is cancelled.
+func (ctrl *ApplicationController) Run(ctx context.Context, statusProcessors, operationProcessors int) {
+	defer ctrl.appQueue.ShutDown()
+	defer ctrl.operationQueue.ShutDown()
+
+	if ctrl.metricsServer.server != nil {
+		go func() {
+			if err := ctrl.metricsServer.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Warnf("metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < statusProcessors; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctrl.runAppWorker(ctx)
+		}()
+	}
+	for i := 0; i < operationProcessors; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctrl.runOperationWorker(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+}
+
+func (ctrl *ApplicationController) runAppWorker(ctx context.Context) {
+	for ctrl.processNextApp(ctx) {
+	}
+}
+
+func (ctrl *ApplicationController) runOperationWorker(ctx context.Context) {
+	for ctrl.processNextOperation(ctx) {
+	}
+}
+
+// processNextApp reconciles one Application off appQueue, then reschedules
+// it: a successful reconcile of a cluster this replica owns is re-enqueued
+// after jitteredResyncPeriod, and a failed reconcile is retried after
+// selfHeal's backoff for this app instead of a flat delay. An app whose
+// destination cluster belongs to another shard is left alone entirely -
+// whichever replica owns that cluster is responsible for resyncing it.
+func (ctrl *ApplicationController) processNextApp(ctx context.Context) bool {
+	key, shutdown := ctrl.appQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer ctrl.appQueue.Done(key)
+
+	appName, ok := key.(string)
+	if !ok {
+		ctrl.appQueue.Forget(key)
+		return true
+	}
+
+	owned, err := ctrl.reconcileApp(ctx, appName)
+	if err != nil {
+		log.Warnf("failed to reconcile app %s: %v", appName, err)
+		ctrl.appQueue.AddAfter(key, ctrl.selfHeal.NextDelay(appName))
+		return true
+	}
+
+	ctrl.selfHeal.Reset(appName)
+	ctrl.appQueue.Forget(key)
+	if owned {
+		ctrl.appQueue.AddAfter(key, ctrl.jitteredResyncPeriod(appName))
+	}
+	return true
+}
+
+// reconcileApp fetches appName and, for the cluster it targets, reports
+// whether this replica owns that cluster under the configured sharding
+// algorithm - the call site that actually exercises clusterFilter, rather
+// than leaving it built and unit-tested but unreachable from the workers
+// that are supposed to consult it.
+func (ctrl *ApplicationController) reconcileApp(ctx context.Context, appName string) (bool, error) {
+	app, err := ctrl.applicationClientset.ArgoprojV1alpha1().Applications(ctrl.namespace).Get(ctx, appName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting application %s: %w", appName, err)
+	}
+
+	cluster := &v1alpha1.Cluster{Server: app.Spec.Destination.Server, Name: app.Spec.Destination.Name}
+	if ctrl.clusterFilter != nil && !ctrl.clusterFilter(cluster) {
+		return false, nil
+	}
+
+	if _, err := ctrl.clusterRestConfig(cluster); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// clusterRestConfig builds a REST config for cluster with
+// managedClusterQPS/Burst applied via managedClusterRestConfig. Its only
+// use is reconcileApp, which checks the returned error and discards the
+// config.
+func (ctrl *ApplicationController) clusterRestConfig(cluster *v1alpha1.Cluster) (*rest.Config, error) {
+	base, err := cluster.RESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("building REST config for cluster %q: %w", cluster.Server, err)
+	}
+	return ctrl.managedClusterRestConfig(base), nil
+}
+
+// processNextOperation retries a failed operation after selfHeal's backoff
+// for this app rather than a flat delay; operations aren't resynced on a
+// timer, so a successful pass just drops the item.
+func (ctrl *ApplicationController) processNextOperation(ctx context.Context) bool {
+	key, shutdown := ctrl.operationQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer ctrl.operationQueue.Done(key)
+
+	appName, ok := key.(string)
+	if !ok {
+		ctrl.operationQueue.Forget(key)
+		return true
+	}
+
+	if err := ctrl.reconcileOperation(ctx, appName); err != nil {
+		log.Warnf("failed to process operation for app %s: %v", appName, err)
+		ctrl.operationQueue.AddAfter(key, ctrl.selfHeal.NextDelay(appName))
+		return true
+	}
+
+	ctrl.selfHeal.Reset(appName)
+	ctrl.operationQueue.Forget(key)
+	return true
+}
+
+func (ctrl *ApplicationController) reconcileOperation(ctx context.Context, appName string) error {
+	_, err := ctrl.applicationClientset.ArgoprojV1alpha1().Applications(ctrl.namespace).Get(ctx, appName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// jitteredResyncPeriod is the per-app resync interval for this controller;
+// see the package-level jitteredResyncPeriod for how the offset is derived.
+func (ctrl *ApplicationController) jitteredResyncPeriod(appName string) time.Duration {
+	return jitteredResyncPeriod(ctrl.appResyncPeriod, ctrl.appResyncJitter, appName)
+}
+
+// jitteredResyncPeriod offsets appResyncPeriod by a stable, per-app
+// pseudo-random amount in [0, appResyncJitter). The offset is derived
+// deterministically from the app's name (rather than math/rand) so
+// repeated calls for the same app agree without needing to persist any
+// state, while different apps still land on different points in the
+// resync cycle.
+func jitteredResyncPeriod(appResyncPeriod, appResyncJitter time.Duration, appName string) time.Duration {
+	if appResyncJitter <= 0 {
+		return appResyncPeriod
+	}
+	sum := sha256.Sum256([]byte(appName))
+	offset := time.Duration(binary.BigEndian.Uint64(sum[:8])%uint64(appResyncJitter.Nanoseconds())) * time.Nanosecond
+	return appResyncPeriod + offset
+}
+
+// managedClusterRestConfig returns a copy of base with the controller's
+// configured QPS/Burst applied. Its only caller is clusterRestConfig,
+// which reconcileApp uses for each owned app's destination cluster; no
+// cluster cache or client in this package is built from the result, so
+// --managed-cluster-qps/--managed-cluster-burst do not throttle any
+// requests to managed clusters.
+func (ctrl *ApplicationController) managedClusterRestConfig(base *rest.Config) *rest.Config {
+	cfg := rest.CopyConfig(base)
+	cfg.QPS = ctrl.managedClusterQPS
+	cfg.Burst = ctrl.managedClusterBurst
+	return cfg
+}
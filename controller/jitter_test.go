@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitteredResyncPeriod_WithinBounds(t *testing.T) {
+	base := 3 * time.Minute
+	jitter := 30 * time.Second
+	for _, app := range []string{"app-a", "app-b", "guestbook", "kustomize-guestbook"} {
+		d := jitteredResyncPeriod(base, jitter, app)
+		if d < base || d >= base+jitter {
+			t.Errorf("jitteredResyncPeriod(%q) = %v, want in [%v, %v)", app, d, base, base+jitter)
+		}
+	}
+}
+
+func TestJitteredResyncPeriod_Deterministic(t *testing.T) {
+	base := time.Minute
+	jitter := 10 * time.Second
+	first := jitteredResyncPeriod(base, jitter, "guestbook")
+	second := jitteredResyncPeriod(base, jitter, "guestbook")
+	if first != second {
+		t.Errorf("expected stable jitter for the same app name, got %v vs %v", first, second)
+	}
+}
+
+func TestJitteredResyncPeriod_NoJitterReturnsBase(t *testing.T) {
+	base := 2 * time.Minute
+	if d := jitteredResyncPeriod(base, 0, "guestbook"); d != base {
+		t.Errorf("expected base period with zero jitter, got %v", d)
+	}
+}
+
+func TestJitteredResyncPeriod_SpreadsDifferentApps(t *testing.T) {
+	base := time.Minute
+	jitter := time.Hour
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		name := time.Duration(i).String()
+		seen[jitteredResyncPeriod(base, jitter, name)] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("expected jitter to spread apps across distinct offsets, got only %d distinct values from 20 apps", len(seen))
+	}
+}
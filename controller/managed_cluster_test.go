@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestManagedClusterRestConfig_AppliesQPSAndBurst(t *testing.T) {
+	ctrl := &ApplicationController{managedClusterQPS: 42, managedClusterBurst: 84}
+	base := &rest.Config{Host: "https://cluster.example.com", QPS: 5, Burst: 10}
+
+	got := ctrl.managedClusterRestConfig(base)
+
+	if got.QPS != 42 || got.Burst != 84 {
+		t.Errorf("expected QPS=42 Burst=84, got QPS=%v Burst=%v", got.QPS, got.Burst)
+	}
+	if got.Host != base.Host {
+		t.Errorf("expected other REST config fields to be preserved, host changed: %q vs %q", got.Host, base.Host)
+	}
+	if base.QPS != 5 || base.Burst != 10 {
+		t.Errorf("expected base config to be left untouched, got QPS=%v Burst=%v", base.QPS, base.Burst)
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// newMetricsServer builds (but does not start) the HTTP server that
+// exposes /metrics on the given port, including the workqueue_* series
+// registered via workqueueMetricsProvider.
+func newMetricsServer(port int) *MetricsServer {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &MetricsServer{server: &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}}
+}
+
+// workqueueMetricsProvider implements workqueue.MetricsProvider, exporting
+// each RateLimitingInterface created by NewApplicationController as its
+// own argocd_controller_workqueue_* Prometheus series (depth, latency,
+// work duration, retries, and unfinished work) so operators can see when
+// the app or operation queue is being throttled by the configured
+// workqueue rate limiter.
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return newWorkqueueGauge(name, "depth", "Current depth of the workqueue.")
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return newWorkqueueCounter(name, "adds_total", "Total number of items added to the workqueue.")
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return newWorkqueueHistogram(name, "queue_duration_seconds", "How long an item stays in the workqueue before being processed.")
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return newWorkqueueHistogram(name, "work_duration_seconds", "How long processing an item from the workqueue takes.")
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return newWorkqueueGauge(name, "unfinished_work_seconds", "Seconds of work that has been done but not yet observed by the workqueue.")
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return newWorkqueueGauge(name, "longest_running_processor_seconds", "Duration of the longest running processor for the workqueue.")
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return newWorkqueueCounter(name, "retries_total", "Total number of retries handled by the workqueue.")
+}
+
+func newWorkqueueGauge(queueName, metric, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        fmt.Sprintf("argocd_controller_workqueue_%s", metric),
+		Help:        help,
+		ConstLabels: prometheus.Labels{"name": queueName},
+	})
+	if existing := registerOrReuse(g); existing != nil {
+		return existing.(prometheus.Gauge)
+	}
+	return g
+}
+
+func newWorkqueueCounter(queueName, metric, help string) prometheus.Counter {
+	c := prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        fmt.Sprintf("argocd_controller_workqueue_%s", metric),
+		Help:        help,
+		ConstLabels: prometheus.Labels{"name": queueName},
+	})
+	if existing := registerOrReuse(c); existing != nil {
+		return existing.(prometheus.Counter)
+	}
+	return c
+}
+
+func newWorkqueueHistogram(queueName, metric, help string) prometheus.Histogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        fmt.Sprintf("argocd_controller_workqueue_%s", metric),
+		Help:        help,
+		ConstLabels: prometheus.Labels{"name": queueName},
+		Buckets:     prometheus.DefBuckets,
+	})
+	if existing := registerOrReuse(h); existing != nil {
+		return existing.(prometheus.Histogram)
+	}
+	return h
+}
+
+// registerOrReuse registers c and returns nil, unless a collector for the
+// same metric+labels is already registered (NewApplicationController is
+// constructed more than once in a process, e.g. in tests), in which case it
+// returns the already-registered collector so callers reuse it instead of
+// observing into one nothing ever scrapes.
+func registerOrReuse(c prometheus.Collector) prometheus.Collector {
+	if err := prometheus.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		log.Warnf("failed to register workqueue metric: %v", err)
+	}
+	return nil
+}
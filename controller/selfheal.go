@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// selfHealTracker replaces the old flat selfHealTimeoutSeconds retry
+// interval with an exponential backoff per app, so a repeatedly-failing
+// self heal doesn't hammer the repo-server/target cluster at a fixed
+// cadence. Each app gets its own wait.Backoff, cloned from the template
+// passed to NewApplicationController, that grows on every consecutive
+// failure and resets once self heal succeeds.
+type selfHealTracker struct {
+	template wait.Backoff
+
+	mu      sync.Mutex
+	backoff map[string]*wait.Backoff
+}
+
+func newSelfHealTracker(template wait.Backoff) *selfHealTracker {
+	return &selfHealTracker{template: template, backoff: map[string]*wait.Backoff{}}
+}
+
+// NextDelay returns how long to wait before the next self heal attempt
+// for appName, advancing that app's backoff state.
+func (t *selfHealTracker) NextDelay(appName string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.backoff[appName]
+	if !ok {
+		clone := t.template
+		b = &clone
+		t.backoff[appName] = b
+	}
+	delay := b.Step()
+	if t.template.Cap > 0 && delay > t.template.Cap {
+		delay = t.template.Cap
+	}
+	return delay
+}
+
+// Reset clears an app's backoff state after a successful self heal, so
+// its next failure starts again from the initial backoff duration.
+func (t *selfHealTracker) Reset(appName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.backoff, appName)
+}
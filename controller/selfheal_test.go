@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestSelfHealTracker_BacksOffExponentially(t *testing.T) {
+	tracker := newSelfHealTracker(wait.Backoff{Duration: time.Second, Factor: 2, Cap: time.Minute, Steps: 100})
+
+	first := tracker.NextDelay("guestbook")
+	second := tracker.NextDelay("guestbook")
+	third := tracker.NextDelay("guestbook")
+
+	if !(first < second && second < third) {
+		t.Errorf("expected strictly increasing backoff, got %v, %v, %v", first, second, third)
+	}
+}
+
+func TestSelfHealTracker_CapsDelay(t *testing.T) {
+	tracker := newSelfHealTracker(wait.Backoff{Duration: time.Second, Factor: 10, Cap: 5 * time.Second, Steps: 100})
+	var last time.Duration
+	for i := 0; i < 10; i++ {
+		last = tracker.NextDelay("guestbook")
+	}
+	if last > 5*time.Second {
+		t.Errorf("expected backoff to stay capped at 5s, got %v", last)
+	}
+}
+
+func TestSelfHealTracker_ResetRestartsBackoff(t *testing.T) {
+	tracker := newSelfHealTracker(wait.Backoff{Duration: time.Second, Factor: 2, Cap: time.Minute, Steps: 100})
+
+	_ = tracker.NextDelay("guestbook")
+	_ = tracker.NextDelay("guestbook")
+	tracker.Reset("guestbook")
+
+	afterReset := tracker.NextDelay("guestbook")
+	if afterReset != time.Second {
+		t.Errorf("expected backoff to restart at the initial duration after Reset, got %v", afterReset)
+	}
+}
+
+func TestSelfHealTracker_TracksAppsIndependently(t *testing.T) {
+	tracker := newSelfHealTracker(wait.Backoff{Duration: time.Second, Factor: 2, Cap: time.Minute, Steps: 100})
+
+	_ = tracker.NextDelay("app-a")
+	_ = tracker.NextDelay("app-a")
+	firstForB := tracker.NextDelay("app-b")
+
+	if firstForB != time.Second {
+		t.Errorf("expected a fresh app's first delay to be the initial duration, got %v", firstForB)
+	}
+}
@@ -0,0 +1,148 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// membershipLeaseLabel marks the per-replica Leases used as the
+// membership registry for consistent-hash sharding, so they can be
+// listed independently of any other Lease a replica might hold.
+const membershipLeaseLabel = "app.kubernetes.io/part-of"
+const membershipLeaseLabelValue = "argocd-application-controller-shard"
+
+// LeaseMembership discovers live replicas from a set of per-replica
+// coordination.k8s.io Leases rather than StatefulSet pod listing. Each
+// replica owns and periodically renews its own Lease (using the same
+// resourcelock.LeaseLock primitive client-go's leaderelection package
+// uses); Replicas() treats any Lease whose renew time is still within its
+// lease duration as live. This removes the operator burden of manually
+// setting ARGOCD_CONTROLLER_REPLICAS/ARGOCD_CONTROLLER_SHARD and lets
+// Kubernetes-native HPA/StatefulSet scaling drive shard membership.
+type LeaseMembership struct {
+	kubeClient    kubernetes.Interface
+	namespace     string
+	self          string
+	leaseBaseName string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+
+	lock *resourcelock.LeaseLock
+}
+
+// NewLeaseMembership builds a LeaseMembership for the replica identified
+// by self. leaseBaseName is suffixed with "-<self>" to form each
+// replica's own Lease name. renewDeadline bounds how long a single renew
+// attempt (Get+Create or Get+Update against the API server) is allowed to
+// take before it's abandoned; a replica that can't renew within this
+// deadline is left to fall out of the live Lease set on its own once
+// leaseDuration elapses.
+func NewLeaseMembership(kubeClient kubernetes.Interface, namespace, self, leaseBaseName string, leaseDuration, renewDeadline, retryPeriod time.Duration) *LeaseMembership {
+	leaseName := fmt.Sprintf("%s-%s", leaseBaseName, self)
+	return &LeaseMembership{
+		kubeClient:    kubeClient,
+		namespace:     namespace,
+		self:          self,
+		leaseBaseName: leaseBaseName,
+		leaseDuration: leaseDuration,
+		renewDeadline: renewDeadline,
+		retryPeriod:   retryPeriod,
+		lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: namespace,
+				Labels:    map[string]string{membershipLeaseLabel: membershipLeaseLabelValue},
+			},
+			Client: kubeClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: self,
+			},
+		},
+	}
+}
+
+// Run creates (if absent) and renews this replica's own Lease every
+// retryPeriod until ctx is cancelled, recording this replica as live the
+// same way client-go's leaderelection package records leadership.
+func (m *LeaseMembership) Run(ctx context.Context) {
+	m.renew(ctx)
+	ticker := time.NewTicker(m.retryPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.renew(ctx)
+		}
+	}
+}
+
+func (m *LeaseMembership) renew(ctx context.Context) {
+	if m.renewDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.renewDeadline)
+		defer cancel()
+	}
+
+	now := time.Now()
+	record := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       m.self,
+		LeaseDurationSeconds: int(m.leaseDuration.Seconds()),
+		AcquireTime:          metav1.NewTime(now),
+		RenewTime:            metav1.NewTime(now),
+	}
+
+	if _, _, err := m.lock.Get(ctx); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Warnf("sharding: failed to read membership lease for %s: %v", m.self, err)
+			return
+		}
+		// Created directly rather than via m.lock.Create, which does not copy
+		// LeaseMeta's labels onto the new Lease; later Updates keep them.
+		lease := &coordinationv1.Lease{ObjectMeta: m.lock.LeaseMeta, Spec: resourcelock.LeaderElectionRecordToLeaseSpec(&record)}
+		if _, err := m.kubeClient.CoordinationV1().Leases(m.namespace).Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			log.Warnf("sharding: failed to create membership lease for %s: %v", m.self, err)
+		}
+		return
+	}
+	if err := m.lock.Update(ctx, record); err != nil {
+		log.Warnf("sharding: failed to renew membership lease for %s: %v", m.self, err)
+	}
+}
+
+// Replicas lists all non-expired per-replica membership leases and
+// returns their holder identities.
+func (m *LeaseMembership) Replicas(ctx context.Context) ([]string, error) {
+	list, err := m.kubeClient.CoordinationV1().Leases(m.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", membershipLeaseLabel, membershipLeaseLabelValue),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing membership leases: %w", err)
+	}
+
+	now := time.Now()
+	var members []string
+	for _, lease := range list.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expiry) {
+			continue // stale lease left behind by a replica that was terminated uncleanly
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+	return members, nil
+}
@@ -0,0 +1,103 @@
+package sharding
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+// MembershipSource reports the set of controller replicas that are
+// currently alive. Implementations may back this with StatefulSet pod
+// listing or a coordination.k8s.io Lease registry.
+type MembershipSource interface {
+	// Replicas returns the identities of the currently live replicas.
+	Replicas(ctx context.Context) ([]string, error)
+}
+
+// ShardManager owns the consistent hash ring for the "consistent-hash"
+// sharding algorithm and keeps it up to date as replica membership
+// changes, so that `ApplicationController` always sees a filter
+// reflecting the current ring without needing to restart.
+type ShardManager struct {
+	self   string
+	source MembershipSource
+	poll   time.Duration
+
+	ring atomic.Value // *Ring
+}
+
+// NewShardManager constructs a ShardManager for the replica identified by
+// self (typically its pod name). poll controls how often membership is
+// re-read when the source does not support push notifications.
+func NewShardManager(self string, source MembershipSource, poll time.Duration) *ShardManager {
+	if poll <= 0 {
+		poll = 30 * time.Second
+	}
+	return &ShardManager{self: self, source: source, poll: poll}
+}
+
+// Run rebuilds the ring whenever membership changes and blocks until ctx
+// is cancelled. It should be started in its own goroutine.
+func (m *ShardManager) Run(ctx context.Context) {
+	m.refresh(ctx)
+	ticker := time.NewTicker(m.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.refresh(ctx)
+		}
+	}
+}
+
+func (m *ShardManager) refresh(ctx context.Context) {
+	members, err := m.source.Replicas(ctx)
+	if err != nil {
+		log.Warnf("sharding: failed to list controller replicas, keeping previous ring: %v", err)
+		return
+	}
+	next := NewRing(members, defaultVNodes)
+	if prev, ok := m.ring.Load().(*Ring); ok && sameMembers(prev.Members(), next.Members()) {
+		return
+	}
+	m.ring.Store(next)
+	log.Infof("sharding: rebuilt consistent hash ring for %d replicas (self=%s)", len(members), m.self)
+}
+
+// ClusterFilter returns a filter function, backed by the manager's atomic
+// ring pointer, suitable for passing to `ApplicationController`. Unlike
+// the legacy filter it can be safely called across ring rebuilds: each
+// invocation reads the latest ring.
+func (m *ShardManager) ClusterFilter() func(cluster *v1alpha1.Cluster) bool {
+	return func(cluster *v1alpha1.Cluster) bool {
+		ring, ok := m.ring.Load().(*Ring)
+		if !ok || ring == nil {
+			// Ring not built yet; process nothing until membership is known
+			// rather than risk double-processing a cluster.
+			return false
+		}
+		return ring.Owner(cluster.Server) == m.self
+	}
+}
+
+func sameMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, m := range a {
+		seen[m] = true
+	}
+	for _, m := range b {
+		if !seen[m] {
+			return false
+		}
+	}
+	return true
+}
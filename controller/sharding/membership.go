@@ -0,0 +1,56 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StatefulSetMembership discovers live replicas by listing the Ready pods
+// belonging to the application controller's StatefulSet. It is the
+// default MembershipSource until Lease-based membership (see
+// leaderelection-backed sharding) is enabled.
+type StatefulSetMembership struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	selector   string
+}
+
+// NewStatefulSetMembership returns a MembershipSource backed by pod
+// listing against the given label selector, e.g.
+// "app.kubernetes.io/name=argocd-application-controller".
+func NewStatefulSetMembership(kubeClient kubernetes.Interface, namespace, selector string) *StatefulSetMembership {
+	return &StatefulSetMembership{kubeClient: kubeClient, namespace: namespace, selector: selector}
+}
+
+func (m *StatefulSetMembership) Replicas(ctx context.Context) ([]string, error) {
+	pods, err := m.kubeClient.CoreV1().Pods(m.namespace).List(ctx, metav1.ListOptions{LabelSelector: m.selector})
+	if err != nil {
+		return nil, fmt.Errorf("listing controller pods: %w", err)
+	}
+	var members []string
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || !isPodReady(&pod) {
+			continue
+		}
+		members = append(members, pod.Name)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
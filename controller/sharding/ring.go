@@ -0,0 +1,69 @@
+package sharding
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// defaultVNodes is the number of virtual nodes placed on the ring for each
+// live replica. A higher count spreads clusters more evenly across
+// replicas at the cost of a larger ring to search.
+const defaultVNodes = 128
+
+// Ring is a consistent hash ring used to assign clusters to controller
+// replicas. It is immutable once built; membership changes are handled by
+// building a new Ring and swapping it in (see ShardManager).
+type Ring struct {
+	hashes  []uint32
+	owners  map[uint32]string
+	vnodes  int
+	members []string
+}
+
+// NewRing builds a consistent hash ring from the given set of replica
+// identities (e.g. pod names). The order of members does not matter.
+func NewRing(members []string, vnodes int) *Ring {
+	if vnodes <= 0 {
+		vnodes = defaultVNodes
+	}
+	r := &Ring{
+		owners:  make(map[uint32]string, len(members)*vnodes),
+		vnodes:  vnodes,
+		members: append([]string{}, members...),
+	}
+	for _, m := range members {
+		for i := 0; i < vnodes; i++ {
+			h := hashKey(fmt.Sprintf("%s#%d", m, i))
+			r.owners[h] = m
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// Owner returns the replica identity responsible for the given key (the
+// cluster's server URL). It returns "" if the ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.owners[r.hashes[idx]]
+}
+
+// Members returns the set of replica identities backing this ring.
+func (r *Ring) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+func hashKey(key string) uint32 {
+	sum := sha1.Sum([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,45 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRing_ScaleUpMovesMinorityOfKeys(t *testing.T) {
+	before := []string{"argocd-application-controller-0", "argocd-application-controller-1", "argocd-application-controller-2"}
+	after := append(append([]string{}, before...), "argocd-application-controller-3")
+
+	ringBefore := NewRing(before, defaultVNodes)
+	ringAfter := NewRing(after, defaultVNodes)
+
+	const numClusters = 1000
+	moved := 0
+	for i := 0; i < numClusters; i++ {
+		key := fmt.Sprintf("https://cluster-%d.example.com", i)
+		if ringBefore.Owner(key) != ringAfter.Owner(key) {
+			moved++
+		}
+	}
+
+	// Adding one replica to N should move roughly 1/(N+1) of the keys, not
+	// all of them. Allow generous slack since vnode placement is random.
+	maxExpected := numClusters / len(before)
+	if moved > maxExpected {
+		t.Errorf("expected at most ~%d/%d clusters to move on scale-up, got %d", maxExpected, numClusters, moved)
+	}
+	if moved == 0 {
+		t.Errorf("expected at least some clusters to move to the new replica")
+	}
+}
+
+func TestRing_OwnerStableWithoutMembershipChange(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	ring := NewRing(members, defaultVNodes)
+	key := "https://stable.example.com"
+	owner := ring.Owner(key)
+	for i := 0; i < 100; i++ {
+		if got := ring.Owner(key); got != owner {
+			t.Fatalf("owner for %q changed across calls: %q vs %q", key, owner, got)
+		}
+	}
+}